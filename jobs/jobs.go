@@ -0,0 +1,207 @@
+// Copyright 2013 Travis Keep. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or
+// at http://opensource.org/licenses/BSD-3-Clause.
+
+// Package jobs layers a registered, schedulable job model on top of the
+// tasks package, in the spirit of Mattermost's job-server framework:
+// jobs are registered by name against a schedule, a Server owns their
+// runs, and each run's state transitions are persisted to a
+// StatusStore so they can be inspected or canceled later.
+package jobs
+
+import (
+  "errors"
+  "strconv"
+  "sync"
+  "sync/atomic"
+  "time"
+
+  "github.com/keep94/tasks"
+  "github.com/keep94/tasks/recurring"
+)
+
+// ErrRunNotFound is returned by Server.Cancel when no in-progress run
+// has the given run ID.
+var ErrRunNotFound = errors.New("jobs: run not found")
+
+// Factory creates a fresh tasks.Task for a single run of a registered
+// job. Server calls it once per scheduled run so that jobs with
+// per-run state don't leak it between runs.
+type Factory func() tasks.Task
+
+type registration struct {
+  name     string
+  factory  Factory
+  schedule recurring.R
+}
+
+var (
+  registryMu sync.Mutex
+  registry   = make(map[string]*registration)
+)
+
+// Register adds a named job to the package-level registry. schedule
+// determines when a Server runs it; factory is called once per
+// scheduled run to build that run's Task. Registering the same name
+// twice replaces the earlier registration.
+//
+// The registry is shared by every Server in the process: calling
+// Start on more than one Server runs every registered job on each of
+// them. Tests that register jobs should give each a unique name and
+// call Unregister once done, since nothing else removes an entry.
+func Register(name string, factory Factory, schedule recurring.R) {
+  registryMu.Lock()
+  defer registryMu.Unlock()
+  registry[name] = &registration{name: name, factory: factory, schedule: schedule}
+}
+
+// Unregister removes the named job from the package-level registry.
+// It is a no-op if name was never registered. Jobs already started by
+// a Server's Start are unaffected; Unregister only keeps the job from
+// being picked up by a later Start call.
+func Unregister(name string) {
+  registryMu.Lock()
+  defer registryMu.Unlock()
+  delete(registry, name)
+}
+
+// Server runs the jobs registered with Register on their schedules,
+// persisting each run's status to a StatusStore and letting callers
+// inspect or cancel runs in progress.
+type Server struct {
+  store StatusStore
+
+  mu        sync.Mutex
+  enabled   map[string]bool
+  runs      map[string]*tasks.Execution
+  canceled  map[string]bool
+
+  runIDSeq uint64
+}
+
+// NewServer returns a Server that persists job runs to store. Every
+// registered job starts out enabled.
+func NewServer(store StatusStore) *Server {
+  return &Server{
+    store:    store,
+    enabled:  make(map[string]bool),
+    runs:     make(map[string]*tasks.Execution),
+    canceled: make(map[string]bool),
+  }
+}
+
+// Enable turns the named job on or off. A disabled job's scheduled
+// ticks are skipped rather than run. Jobs are enabled by default.
+func (s *Server) Enable(name string, enabled bool) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+  s.enabled[name] = enabled
+}
+
+func (s *Server) isEnabled(name string) bool {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+  enabled, ok := s.enabled[name]
+  return !ok || enabled
+}
+
+// Start begins running every job currently in the registry on its own
+// schedule, each in its own goroutine. Jobs registered after Start is
+// called are not picked up; call Start again after registering more
+// jobs if needed.
+func (s *Server) Start() {
+  registryMu.Lock()
+  regs := make([]*registration, 0, len(registry))
+  for _, reg := range registry {
+    regs = append(regs, reg)
+  }
+  registryMu.Unlock()
+  for _, reg := range regs {
+    go s.runSchedule(reg)
+  }
+}
+
+func (s *Server) runSchedule(reg *registration) {
+  stream := reg.schedule.ForTime(time.Now())
+  defer stream.Close()
+  var nextT time.Time
+  for {
+    if err := stream.Next(&nextT); err != nil {
+      return
+    }
+    if d := time.Until(nextT); d > 0 {
+      time.Sleep(d)
+    }
+    if !s.isEnabled(reg.name) {
+      continue
+    }
+    s.runOnce(reg)
+  }
+}
+
+func (s *Server) nextRunID() string {
+  return strconv.FormatUint(atomic.AddUint64(&s.runIDSeq, 1), 10)
+}
+
+func (s *Server) runOnce(reg *registration) {
+  runID := s.nextRunID()
+  status := JobStatus{
+    RunID:     runID,
+    Name:      reg.name,
+    Status:    Pending,
+    StartedAt: time.Now(),
+  }
+  s.store.Save(status)
+
+  e := tasks.Start(reg.factory())
+  s.mu.Lock()
+  s.runs[runID] = e
+  s.mu.Unlock()
+
+  status.Status = InProgress
+  s.store.Save(status)
+
+  <-e.Done()
+
+  s.mu.Lock()
+  delete(s.runs, runID)
+  wasCanceled := s.canceled[runID]
+  delete(s.canceled, runID)
+  s.mu.Unlock()
+
+  status.EndedAt = time.Now()
+  switch {
+  case wasCanceled:
+    status.Status = Canceled
+  case e.Error() != nil:
+    status.Status = Error
+    status.LastError = e.Error()
+  default:
+    status.Status = Success
+  }
+  s.store.Save(status)
+}
+
+// Status returns the recorded runs of the named job, most recent
+// first. An empty name returns the runs of every job.
+func (s *Server) Status(name string) []JobStatus {
+  statuses, _ := s.store.List(name)
+  return statuses
+}
+
+// Cancel ends the run with the given run ID, if it is still in
+// progress. It returns ErrRunNotFound if runID names no current run.
+func (s *Server) Cancel(runID string) error {
+  s.mu.Lock()
+  e, ok := s.runs[runID]
+  if ok {
+    s.canceled[runID] = true
+  }
+  s.mu.Unlock()
+  if !ok {
+    return ErrRunNotFound
+  }
+  e.End()
+  return nil
+}
@@ -0,0 +1,145 @@
+// Copyright 2013 Travis Keep. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or
+// at http://opensource.org/licenses/BSD-3-Clause.
+
+package jobs_test
+
+import (
+  "errors"
+  "github.com/keep94/tasks"
+  "github.com/keep94/tasks/jobs"
+  "github.com/keep94/tasks/recurring"
+  "testing"
+  "time"
+)
+
+var kJobError = errors.New("jobs: some error")
+
+// fakeJobTask is a tasks.Task with a controllable duration and error,
+// used the same way fakeTask is used in tasks_test.go.
+type fakeJobTask struct {
+  runDuration time.Duration
+  err         error
+}
+
+func (ft *fakeJobTask) Do(e *tasks.Execution) {
+  if ft.runDuration > 0 {
+    e.Sleep(ft.runDuration)
+  }
+  if ft.err != nil {
+    e.SetError(ft.err)
+  }
+}
+
+func TestServerRunsRegisteredJobToSuccess(t *testing.T) {
+  const name = "test-job-success"
+  jobs.Register(
+      name,
+      func() tasks.Task { return &fakeJobTask{} },
+      recurring.FirstN(recurring.AtInterval(5*time.Millisecond), 1))
+  defer jobs.Unregister(name)
+  store := jobs.NewMemStatusStore()
+  server := jobs.NewServer(store)
+  server.Start()
+
+  statuses := waitForStatus(t, server, name, jobs.Success)
+  if len(statuses) == 0 {
+    t.Fatal("Expected at least one recorded run.")
+  }
+  if statuses[0].LastError != nil {
+    t.Errorf("Expected no error, got %v", statuses[0].LastError)
+  }
+}
+
+func TestServerRunsRegisteredJobToError(t *testing.T) {
+  const name = "test-job-error"
+  jobs.Register(
+      name,
+      func() tasks.Task { return &fakeJobTask{err: kJobError} },
+      recurring.FirstN(recurring.AtInterval(5*time.Millisecond), 1))
+  defer jobs.Unregister(name)
+  store := jobs.NewMemStatusStore()
+  server := jobs.NewServer(store)
+  server.Start()
+
+  statuses := waitForStatus(t, server, name, jobs.Error)
+  if statuses[0].LastError != kJobError {
+    t.Errorf("Expected kJobError, got %v", statuses[0].LastError)
+  }
+}
+
+func TestServerEnableDisable(t *testing.T) {
+  const name = "test-job-disabled"
+  jobs.Register(
+      name,
+      func() tasks.Task { return &fakeJobTask{} },
+      recurring.FirstN(recurring.AtInterval(5*time.Millisecond), 200))
+  defer jobs.Unregister(name)
+  store := jobs.NewMemStatusStore()
+  server := jobs.NewServer(store)
+  server.Enable(name, false)
+  server.Start()
+
+  time.Sleep(50 * time.Millisecond)
+  if statuses, _ := store.List(name); len(statuses) != 0 {
+    t.Errorf("Expected a disabled job not to run, got %d statuses.", len(statuses))
+  }
+
+  server.Enable(name, true)
+  waitForStatus(t, server, name, jobs.Success)
+}
+
+func TestServerCancel(t *testing.T) {
+  const name = "test-job-cancel"
+  jobs.Register(
+      name,
+      func() tasks.Task { return &fakeJobTask{runDuration: time.Hour} },
+      recurring.FirstN(recurring.AtInterval(5*time.Millisecond), 1))
+  defer jobs.Unregister(name)
+  store := jobs.NewMemStatusStore()
+  server := jobs.NewServer(store)
+  server.Start()
+
+  var runID string
+  deadline := time.Now().Add(time.Second)
+  for time.Now().Before(deadline) {
+    for _, status := range server.Status(name) {
+      if status.Status == jobs.InProgress {
+        runID = status.RunID
+      }
+    }
+    if runID != "" {
+      break
+    }
+    time.Sleep(5 * time.Millisecond)
+  }
+  if runID == "" {
+    t.Fatal("Expected the job to reach InProgress before the deadline.")
+  }
+
+  if err := server.Cancel(runID); err != nil {
+    t.Fatalf("Cancel returned error: %v", err)
+  }
+  waitForStatus(t, server, name, jobs.Canceled)
+
+  if err := server.Cancel(runID); err != jobs.ErrRunNotFound {
+    t.Errorf("Expected ErrRunNotFound for a finished run, got %v", err)
+  }
+}
+
+func waitForStatus(t *testing.T, server *jobs.Server, name string, want jobs.Status) []jobs.JobStatus {
+  t.Helper()
+  deadline := time.Now().Add(time.Second)
+  for time.Now().Before(deadline) {
+    statuses := server.Status(name)
+    for _, status := range statuses {
+      if status.Status == want {
+        return statuses
+      }
+    }
+    time.Sleep(5 * time.Millisecond)
+  }
+  t.Fatalf("Timed out waiting for job %q to reach status %v", name, want)
+  return nil
+}
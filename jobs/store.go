@@ -0,0 +1,171 @@
+// Copyright 2013 Travis Keep. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or
+// at http://opensource.org/licenses/BSD-3-Clause.
+
+package jobs
+
+import (
+  "database/sql"
+  "errors"
+  "sort"
+  "sync"
+  "time"
+)
+
+// Status is the state of a single run of a registered job.
+type Status int
+
+const (
+  Pending Status = iota
+  InProgress
+  Success
+  Error
+  Canceled
+)
+
+func (s Status) String() string {
+  switch s {
+  case Pending:
+    return "pending"
+  case InProgress:
+    return "in progress"
+  case Success:
+    return "success"
+  case Error:
+    return "error"
+  case Canceled:
+    return "canceled"
+  default:
+    return "unknown"
+  }
+}
+
+// JobStatus records the state of one run of a registered job.
+type JobStatus struct {
+  RunID     string
+  Name      string
+  Status    Status
+  StartedAt time.Time
+  EndedAt   time.Time
+  LastError error
+}
+
+// StatusStore persists the state transitions Server records for each
+// run of a registered job.
+type StatusStore interface {
+
+  // Save records status, replacing any existing record with the same
+  // RunID. Server calls Save once when a run is created and again
+  // each time its Status changes.
+  Save(status JobStatus) error
+
+  // List returns every recorded JobStatus for the job with the given
+  // name, most recent first. An empty name matches every job.
+  List(name string) ([]JobStatus, error)
+}
+
+// NewMemStatusStore returns a StatusStore that keeps JobStatus records
+// in memory.
+func NewMemStatusStore() StatusStore {
+  return &memStatusStore{byRunID: make(map[string]JobStatus)}
+}
+
+type memStatusStore struct {
+  mu      sync.Mutex
+  byRunID map[string]JobStatus
+}
+
+func (s *memStatusStore) Save(status JobStatus) error {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+  s.byRunID[status.RunID] = status
+  return nil
+}
+
+func (s *memStatusStore) List(name string) ([]JobStatus, error) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+  var result []JobStatus
+  for _, status := range s.byRunID {
+    if name == "" || status.Name == name {
+      result = append(result, status)
+    }
+  }
+  sort.Slice(result, func(i, j int) bool {
+    return result[i].StartedAt.After(result[j].StartedAt)
+  })
+  return result, nil
+}
+
+// NewSQLStatusStore returns a StatusStore backed by db. The caller is
+// responsible for creating a table with the schema:
+//
+//	CREATE TABLE job_status (
+//	  run_id     TEXT PRIMARY KEY,
+//	  name       TEXT NOT NULL,
+//	  status     INTEGER NOT NULL,
+//	  started_at TIMESTAMP NOT NULL,
+//	  ended_at   TIMESTAMP,
+//	  last_error TEXT
+//	)
+func NewSQLStatusStore(db *sql.DB) StatusStore {
+  return &sqlStatusStore{db: db}
+}
+
+type sqlStatusStore struct {
+  db *sql.DB
+}
+
+func (s *sqlStatusStore) Save(status JobStatus) error {
+  var lastErr sql.NullString
+  if status.LastError != nil {
+    lastErr = sql.NullString{String: status.LastError.Error(), Valid: true}
+  }
+  var endedAt sql.NullTime
+  if !status.EndedAt.IsZero() {
+    endedAt = sql.NullTime{Time: status.EndedAt, Valid: true}
+  }
+  _, err := s.db.Exec(
+      `REPLACE INTO job_status (run_id, name, status, started_at, ended_at, last_error)
+       VALUES (?, ?, ?, ?, ?, ?)`,
+      status.RunID, status.Name, int(status.Status), status.StartedAt, endedAt, lastErr)
+  return err
+}
+
+func (s *sqlStatusStore) List(name string) ([]JobStatus, error) {
+  var rows *sql.Rows
+  var err error
+  if name == "" {
+    rows, err = s.db.Query(
+        `SELECT run_id, name, status, started_at, ended_at, last_error
+         FROM job_status ORDER BY started_at DESC`)
+  } else {
+    rows, err = s.db.Query(
+        `SELECT run_id, name, status, started_at, ended_at, last_error
+         FROM job_status WHERE name = ? ORDER BY started_at DESC`, name)
+  }
+  if err != nil {
+    return nil, err
+  }
+  defer rows.Close()
+  var result []JobStatus
+  for rows.Next() {
+    var status JobStatus
+    var statusInt int
+    var endedAt sql.NullTime
+    var lastErr sql.NullString
+    if err := rows.Scan(&status.RunID, &status.Name, &statusInt, &status.StartedAt, &endedAt, &lastErr); err != nil {
+      return nil, err
+    }
+    status.Status = Status(statusInt)
+    if endedAt.Valid {
+      status.EndedAt = endedAt.Time
+    }
+    if lastErr.Valid {
+      status.LastError = errors.New(lastErr.String)
+    }
+    result = append(result, status)
+  }
+  return result, rows.Err()
+}
@@ -0,0 +1,39 @@
+// Copyright 2013 Travis Keep. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or
+// at http://opensource.org/licenses/BSD-3-Clause.
+
+package tasks
+
+import "sync"
+
+// ParallelTasksN returns a Task like ParallelTasks, except that at most
+// n of taskList run concurrently at any time rather than all of them at
+// once. It is meant for fanning out into many I/O-bound child tasks
+// (HTTP fetches, DB queries) without exhausting file descriptors or
+// overwhelming whatever those tasks call into. As with ParallelTasks,
+// End signals and awaits every child, and Error returns the first
+// non-nil child error.
+func ParallelTasksN(n int, taskList ...Task) Task {
+  if n < 1 {
+    n = 1
+  }
+  return taskFunc(func(e *Execution) {
+    work := make(chan Task)
+    var wg sync.WaitGroup
+    wg.Add(n)
+    for i := 0; i < n; i++ {
+      go func() {
+        defer wg.Done()
+        for t := range work {
+          t.Do(e)
+        }
+      }()
+    }
+    for _, t := range taskList {
+      work <- t
+    }
+    close(work)
+    wg.Wait()
+  })
+}
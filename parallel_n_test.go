@@ -0,0 +1,100 @@
+// Copyright 2013 Travis Keep. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or
+// at http://opensource.org/licenses/BSD-3-Clause.
+
+package tasks_test
+
+import (
+  "errors"
+  "github.com/keep94/tasks"
+  "sync/atomic"
+  "testing"
+  "time"
+)
+
+var kParallelNError = errors.New("tasks: parallel N error")
+
+// concurrencyTrackingTask records how many instances of it are running
+// at once, for verifying ParallelTasksN's concurrency bound.
+type concurrencyTrackingTask struct {
+  running  *int32
+  maxSeen  *int32
+  err      error
+}
+
+func (ct *concurrencyTrackingTask) Do(e *tasks.Execution) {
+  cur := atomic.AddInt32(ct.running, 1)
+  for {
+    prev := atomic.LoadInt32(ct.maxSeen)
+    if cur <= prev || atomic.CompareAndSwapInt32(ct.maxSeen, prev, cur) {
+      break
+    }
+  }
+  time.Sleep(5 * time.Millisecond)
+  atomic.AddInt32(ct.running, -1)
+  if ct.err != nil {
+    e.SetError(ct.err)
+  }
+}
+
+func TestParallelTasksNBoundsConcurrency(t *testing.T) {
+  var running, maxSeen int32
+  testTasks := make([]tasks.Task, 20)
+  for i := range testTasks {
+    testTasks[i] = &concurrencyTrackingTask{running: &running, maxSeen: &maxSeen}
+  }
+  if err := tasks.Run(tasks.ParallelTasksN(3, testTasks...)); err != nil {
+    t.Errorf("Expected no error, got %v", err)
+  }
+  if maxSeen > 3 {
+    t.Errorf("Expected at most 3 tasks running at once, saw %d", maxSeen)
+  }
+  if maxSeen < 1 {
+    t.Error("Expected at least one task to have run.")
+  }
+}
+
+func TestParallelTasksNRunsEveryTask(t *testing.T) {
+  testTasks := make([]*fakeTask, 10)
+  asTasks := make([]tasks.Task, len(testTasks))
+  for i := range testTasks {
+    testTasks[i] = &fakeTask{}
+    asTasks[i] = testTasks[i]
+  }
+  tasks.Run(tasks.ParallelTasksN(2, asTasks...))
+  for i, ft := range testTasks {
+    if !ft.hasRun() {
+      t.Errorf("Expected task %d to have run.", i)
+    }
+  }
+}
+
+func TestParallelTasksNFirstError(t *testing.T) {
+  var running, maxSeen int32
+  testTasks := []tasks.Task{
+    &concurrencyTrackingTask{running: &running, maxSeen: &maxSeen, err: kParallelNError},
+    &concurrencyTrackingTask{running: &running, maxSeen: &maxSeen},
+  }
+  err := tasks.Run(tasks.ParallelTasksN(1, testTasks...))
+  if err != kParallelNError {
+    t.Errorf("Expected kParallelNError, got %v", err)
+  }
+}
+
+func TestParallelTasksNEnd(t *testing.T) {
+  testTasks := make([]*fakeTask, 5)
+  asTasks := make([]tasks.Task, len(testTasks))
+  for i := range testTasks {
+    testTasks[i] = &fakeTask{runDuration: time.Hour}
+    asTasks[i] = testTasks[i]
+  }
+  e := tasks.Start(tasks.ParallelTasksN(2, asTasks...))
+  e.End()
+  <-e.Done()
+  for i, ft := range testTasks {
+    if !ft.hasRun() {
+      t.Errorf("Expected task %d to have run.", i)
+    }
+  }
+}
@@ -0,0 +1,129 @@
+// Copyright 2013 Travis Keep. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or
+// at http://opensource.org/licenses/BSD-3-Clause.
+
+package recurring_test
+
+import (
+  "github.com/keep94/tasks/recurring"
+  "testing"
+  "time"
+)
+
+func TestCronEveryMinute(t *testing.T) {
+  r, err := recurring.Cron("* * * * *")
+  if err != nil {
+    t.Fatalf("Cron returned error: %v", err)
+  }
+  start := time.Date(2013, 9, 12, 17, 21, 30, 0, time.Local)
+  verifyCronTimes(
+      t, r, start,
+      time.Date(2013, 9, 12, 17, 22, 0, 0, time.Local),
+      time.Date(2013, 9, 12, 17, 23, 0, 0, time.Local))
+}
+
+func TestCronStep(t *testing.T) {
+  r, err := recurring.Cron("*/15 * * * *")
+  if err != nil {
+    t.Fatalf("Cron returned error: %v", err)
+  }
+  start := time.Date(2013, 9, 12, 17, 1, 0, 0, time.Local)
+  verifyCronTimes(
+      t, r, start,
+      time.Date(2013, 9, 12, 17, 15, 0, 0, time.Local),
+      time.Date(2013, 9, 12, 17, 30, 0, 0, time.Local),
+      time.Date(2013, 9, 12, 17, 45, 0, 0, time.Local),
+      time.Date(2013, 9, 12, 18, 0, 0, 0, time.Local))
+}
+
+func TestCronRangeAndList(t *testing.T) {
+  r, err := recurring.Cron("0 9-10,14 * * *")
+  if err != nil {
+    t.Fatalf("Cron returned error: %v", err)
+  }
+  start := time.Date(2013, 9, 12, 0, 0, 0, 0, time.Local)
+  verifyCronTimes(
+      t, r, start,
+      time.Date(2013, 9, 12, 9, 0, 0, 0, time.Local),
+      time.Date(2013, 9, 12, 10, 0, 0, 0, time.Local),
+      time.Date(2013, 9, 12, 14, 0, 0, 0, time.Local),
+      time.Date(2013, 9, 13, 9, 0, 0, 0, time.Local))
+}
+
+func TestCronShorthands(t *testing.T) {
+  hourly, err := recurring.Cron("@hourly")
+  if err != nil {
+    t.Fatalf("Cron returned error: %v", err)
+  }
+  explicit, err := recurring.Cron("0 * * * *")
+  if err != nil {
+    t.Fatalf("Cron returned error: %v", err)
+  }
+  start := time.Date(2013, 9, 12, 17, 21, 0, 0, time.Local)
+  var gotHourly, gotExplicit time.Time
+  hs := hourly.ForTime(start)
+  es := explicit.ForTime(start)
+  if err := hs.Next(&gotHourly); err != nil {
+    t.Fatalf("@hourly Next returned error: %v", err)
+  }
+  if err := es.Next(&gotExplicit); err != nil {
+    t.Fatalf("0 * * * * Next returned error: %v", err)
+  }
+  if !gotHourly.Equal(gotExplicit) {
+    t.Errorf("Expected @hourly to equal '0 * * * *', got %v vs %v", gotHourly, gotExplicit)
+  }
+}
+
+func TestCronMonthRollover(t *testing.T) {
+  r, err := recurring.Cron("0 0 1 * *")
+  if err != nil {
+    t.Fatalf("Cron returned error: %v", err)
+  }
+  start := time.Date(2013, 12, 15, 0, 0, 0, 0, time.Local)
+  verifyCronTimes(
+      t, r, start,
+      time.Date(2014, 1, 1, 0, 0, 0, 0, time.Local),
+      time.Date(2014, 2, 1, 0, 0, 0, 0, time.Local))
+}
+
+func TestCronDayOfMonthOrDayOfWeek(t *testing.T) {
+  // 2013-09-12 is a Thursday. 1st and every Monday.
+  r, err := recurring.Cron("0 0 1 * 1")
+  if err != nil {
+    t.Fatalf("Cron returned error: %v", err)
+  }
+  start := time.Date(2013, 9, 12, 0, 0, 0, 0, time.Local)
+  verifyCronTimes(
+      t, r, start,
+      time.Date(2013, 9, 16, 0, 0, 0, 0, time.Local), // Monday
+      time.Date(2013, 9, 23, 0, 0, 0, 0, time.Local), // Monday
+      time.Date(2013, 9, 30, 0, 0, 0, 0, time.Local), // Monday
+      time.Date(2013, 10, 1, 0, 0, 0, 0, time.Local)) // the 1st
+}
+
+func TestCronInvalidSpec(t *testing.T) {
+  if _, err := recurring.Cron("* * * *"); err == nil {
+    t.Error("Expected an error for a spec with too few fields.")
+  }
+  if _, err := recurring.Cron("60 * * * *"); err == nil {
+    t.Error("Expected an error for a minute value out of range.")
+  }
+  if _, err := recurring.Cron("x * * * *"); err == nil {
+    t.Error("Expected an error for a non-numeric field.")
+  }
+}
+
+func verifyCronTimes(t *testing.T, r recurring.R, start time.Time, expected ...time.Time) {
+  s := r.ForTime(start)
+  defer s.Close()
+  for i, want := range expected {
+    var got time.Time
+    if err := s.Next(&got); err != nil {
+      t.Fatalf("Next returned error at index %d: %v", i, err)
+    }
+    if !got.Equal(want) {
+      t.Errorf("At index %d, expected %v, got %v", i, want, got)
+    }
+  }
+}
@@ -8,7 +8,11 @@
 package recurring
 
 import (
+  "container/heap"
+  "fmt"
   "github.com/keep94/gofunctional3/functional"
+  "strconv"
+  "strings"
   "time"
 )
 
@@ -33,10 +37,10 @@ func Combine(rs ...R) R {
   return RFunc(func(t time.Time) functional.Stream {
     streams := make([]functional.Stream, len(rs))
     for i := range rs {
-      streams[i] = rs[i].ForTime(
+      streams[i] = rs[i].ForTime(t)
     }
     return combineStreams(streams)
-  }
+  })
 }
 
 // Modify returns a new R instance that uses f to modify the time.Time
@@ -74,7 +78,7 @@ func AtInterval(d time.Duration) R {
     return &intervalStream{t: t.Add(d), d: d}
   })
 }
-  
+
 // AtTime returns a new R instance that represents repeating at a
 // certain time of day.
 func AtTime(hour24, minute int) R {
@@ -119,24 +123,29 @@ func (n closeDoesNothing) Close() error {
   return nil
 }
 
+// combineStreams merges several ascending time.Time streams into a single
+// ascending stream that emits every time any of them emits.
 func combineStreams(streams []functional.Stream) functional.Stream {
-  h := make(streamHeap, len(streams))
-  for i := range streams {
-    h[i] = &item{stream: streams[i]}
-    h[i].pop()
+  h := make(streamHeap, 0, len(streams))
+  for _, s := range streams {
+    it := &item{stream: s}
+    if it.pop() {
+      h = append(h, it)
+    }
   }
   heap.Init(&h)
-  return &mergeStream{streams: streams, sh: h}
+  return &mergeStream{sh: h}
 }
 
 type item struct {
   stream functional.Stream
   t time.Time
-  e error
 }
 
-func (i *item) pop() {
-  i.e = i.stream.Next(&i.t)
+// pop advances item to its next value, returning false when its stream
+// is exhausted.
+func (i *item) pop() bool {
+  return i.stream.Next(&i.t) == nil
 }
 
 type streamHeap []*item
@@ -146,20 +155,262 @@ func (sh streamHeap) Len() int {
 }
 
 func (sh streamHeap) Less(i, j int) bool {
-  if sh[i].e != nil {
-    return sh[j].e == nil
-  }
-  if sh[j].e != nil {
-    return false
-  }
   return sh[i].t.Before(sh[j].t)
 }
 
 func (sh streamHeap) Swap(i, j int) {
-  return sh[i], sh[j] = sh[j], sh[i]
+  sh[i], sh[j] = sh[j], sh[i]
+}
+
+func (sh *streamHeap) Push(x interface{}) {
+  *sh = append(*sh, x.(*item))
+}
+
+func (sh *streamHeap) Pop() interface{} {
+  old := *sh
+  n := len(old)
+  it := old[n-1]
+  *sh = old[:n-1]
+  return it
+}
+
+type mergeStream struct {
+  sh streamHeap
+}
+
+func (m *mergeStream) Next(ptr interface{}) error {
+  if len(m.sh) == 0 {
+    return functional.Done
+  }
+  it := m.sh[0]
+  p := ptr.(*time.Time)
+  *p = it.t
+  if it.pop() {
+    heap.Fix(&m.sh, 0)
+  } else {
+    heap.Pop(&m.sh)
+  }
+  return nil
+}
+
+func (m *mergeStream) Close() error {
+  for _, it := range m.sh {
+    if err := it.stream.Close(); err != nil {
+      return err
+    }
+  }
+  return nil
+}
+
+// Cron parses a standard 5-field cron expression (minute, hour,
+// day-of-month, month, day-of-week) and returns an R that represents it.
+//
+// Each field accepts "*", "*/N", "A-B", "A,B,C" or combinations thereof
+// (e.g. "1-5,10,20-25/2"). Day-of-month and day-of-week combine with OR
+// semantics as in Vixie cron: if both fields are restricted (not "*"), a
+// time matches if it satisfies either one. The common shorthands
+// "@hourly", "@daily", "@weekly", and "@monthly" are also accepted in
+// place of the 5-field form.
+func Cron(spec string) (R, error) {
+  sched, err := parseCronSpec(spec)
+  if err != nil {
+    return nil, err
+  }
+  return RFunc(func(t time.Time) functional.Stream {
+    return &cronStream{sched: sched, t: t}
+  }), nil
+}
+
+var cronShorthands = map[string]string{
+  "@hourly":  "0 * * * *",
+  "@daily":   "0 0 * * *",
+  "@weekly":  "0 0 * * 0",
+  "@monthly": "0 0 1 * *",
+}
+
+// cronSchedule holds the per-field bitsets a parsed cron expression
+// compiles down to. Bit N of minute is set when minute N matches; hour,
+// dom (day of month, 1-31), and month (1-12) work the same way. dow
+// (day of week, 0-6, Sunday is 0) also accepts 7 for Sunday: parsing
+// sets bit 0 whenever bit 7 is set, so "0" and "7" both work.
+type cronSchedule struct {
+  minute, hour, dom, month, dow uint64
+  domStar, dowStar              bool
+}
+
+func parseCronSpec(spec string) (*cronSchedule, error) {
+  if expanded, ok := cronShorthands[spec]; ok {
+    spec = expanded
+  }
+  fields := strings.Fields(spec)
+  if len(fields) != 5 {
+    return nil, fmt.Errorf("recurring: cron spec must have 5 fields, got %d: %q", len(fields), spec)
+  }
+  minute, err := parseCronField(fields[0], 0, 59)
+  if err != nil {
+    return nil, err
+  }
+  hour, err := parseCronField(fields[1], 0, 23)
+  if err != nil {
+    return nil, err
+  }
+  dom, err := parseCronField(fields[2], 1, 31)
+  if err != nil {
+    return nil, err
+  }
+  month, err := parseCronField(fields[3], 1, 12)
+  if err != nil {
+    return nil, err
+  }
+  dow, err := parseCronField(fields[4], 0, 7)
+  if err != nil {
+    return nil, err
+  }
+  if dow&(1<<7) != 0 {
+    dow |= 1 << 0
+  }
+  return &cronSchedule{
+    minute:  minute,
+    hour:    hour,
+    dom:     dom,
+    month:   month,
+    dow:     dow,
+    domStar: fields[2] == "*",
+    dowStar: fields[4] == "*",
+  }, nil
 }
 
-func (sh streamHeap) Push(x interface{}) {
-  k
+// parseCronField parses a single comma-separated cron field whose values
+// lie in [min, max] and returns the matching values as a bitset.
+func parseCronField(field string, min, max int) (uint64, error) {
+  var bits uint64
+  for _, part := range strings.Split(field, ",") {
+    rangeStart, rangeEnd, step, err := parseCronRange(part, min, max)
+    if err != nil {
+      return 0, fmt.Errorf("recurring: invalid cron field %q: %v", field, err)
+    }
+    for v := rangeStart; v <= rangeEnd; v += step {
+      bits |= 1 << uint(v)
+    }
+  }
+  return bits, nil
+}
 
-  
\ No newline at end of file
+func parseCronRange(part string, min, max int) (rangeStart, rangeEnd, step int, err error) {
+  step = 1
+  if idx := strings.IndexByte(part, '/'); idx >= 0 {
+    step, err = strconv.Atoi(part[idx+1:])
+    if err != nil || step <= 0 {
+      return 0, 0, 0, fmt.Errorf("bad step in %q", part)
+    }
+    part = part[:idx]
+  }
+  switch {
+  case part == "*":
+    rangeStart, rangeEnd = min, max
+  case strings.Contains(part, "-"):
+    bounds := strings.SplitN(part, "-", 2)
+    rangeStart, err = strconv.Atoi(bounds[0])
+    if err != nil {
+      return 0, 0, 0, fmt.Errorf("bad range start in %q", part)
+    }
+    rangeEnd, err = strconv.Atoi(bounds[1])
+    if err != nil {
+      return 0, 0, 0, fmt.Errorf("bad range end in %q", part)
+    }
+  default:
+    rangeStart, err = strconv.Atoi(part)
+    if err != nil {
+      return 0, 0, 0, fmt.Errorf("bad value %q", part)
+    }
+    rangeEnd = rangeStart
+  }
+  if rangeStart < min || rangeEnd > max || rangeStart > rangeEnd {
+    return 0, 0, 0, fmt.Errorf("value out of range [%d, %d] in %q", min, max, part)
+  }
+  return rangeStart, rangeEnd, step, nil
+}
+
+// matchesDay reports whether a day that is day dom (1-31) of its month
+// and falls on weekday dow (0-6, Sunday is 0) matches the schedule's
+// day-of-month and day-of-week fields, combined with Vixie cron's OR
+// semantics.
+func (s *cronSchedule) matchesDay(dom int, dow int) bool {
+  switch {
+  case s.domStar && s.dowStar:
+    return true
+  case s.domStar:
+    return s.dow&(1<<uint(dow)) != 0
+  case s.dowStar:
+    return s.dom&(1<<uint(dom)) != 0
+  default:
+    return s.dom&(1<<uint(dom)) != 0 || s.dow&(1<<uint(dow)) != 0
+  }
+}
+
+// cronStream implements functional.Stream, emitting every wall-clock
+// minute strictly after t that matches sched.
+type cronStream struct {
+  sched *cronSchedule
+  t     time.Time
+  closeDoesNothing
+}
+
+func (cs *cronStream) Next(ptr interface{}) error {
+  next := cs.sched.next(cs.t)
+  cs.t = next
+  p := ptr.(*time.Time)
+  *p = next
+  return nil
+}
+
+// next finds the first minute strictly after t that matches sched by
+// checking field-by-field (month, then day, then hour, then minute).
+// For month, hour and minute it uses the precomputed bitsets to jump
+// straight to the next matching value, rolling over to the next unit
+// whenever a field has no remaining match; days are stepped one at a
+// time through matchesDay.
+func (sched *cronSchedule) next(t time.Time) time.Time {
+  loc := t.Location()
+  t = t.Add(time.Minute).Truncate(time.Minute)
+  for {
+    if v, ok := nextBit(sched.month, int(t.Month()), 12); !ok {
+      t = time.Date(t.Year()+1, 1, 1, 0, 0, 0, 0, loc)
+      continue
+    } else if v != int(t.Month()) {
+      t = time.Date(t.Year(), time.Month(v), 1, 0, 0, 0, 0, loc)
+      continue
+    }
+    if !sched.matchesDay(t.Day(), int(t.Weekday())) {
+      t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+      continue
+    }
+    if v, ok := nextBit(sched.hour, t.Hour(), 23); !ok {
+      t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+      continue
+    } else if v != t.Hour() {
+      t = time.Date(t.Year(), t.Month(), t.Day(), v, 0, 0, 0, loc)
+      continue
+    }
+    if v, ok := nextBit(sched.minute, t.Minute(), 59); !ok {
+      t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+      continue
+    } else if v != t.Minute() {
+      t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), v, 0, 0, loc)
+      continue
+    }
+    return t
+  }
+}
+
+// nextBit returns the lowest bit set in bits that is >= from, scanning up
+// to and including max. ok is false when no such bit exists, meaning the
+// caller must roll over to the next unit.
+func nextBit(bits uint64, from, max int) (v int, ok bool) {
+  for v = from; v <= max; v++ {
+    if bits&(1<<uint(v)) != 0 {
+      return v, true
+    }
+  }
+  return 0, false
+}
@@ -0,0 +1,44 @@
+// Copyright 2013 Travis Keep. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or
+// at http://opensource.org/licenses/BSD-3-Clause.
+
+package tasks
+
+import (
+  "github.com/keep94/tasks/recurring"
+  "time"
+)
+
+// RecurringTask returns a Task that runs t once for each time that r
+// generates from the Execution's current time onward, sleeping between
+// runs. If a run takes longer than the interval to the next scheduled
+// time, that scheduled time is skipped rather than run late. RecurringTask
+// stops early if t reports an error or the Execution is ended.
+func RecurringTask(t Task, r recurring.R) Task {
+  return taskFunc(func(e *Execution) {
+    s := r.ForTime(e.Now())
+    defer s.Close()
+    var nextT time.Time
+    for {
+      if e.IsEnded() {
+        return
+      }
+      if err := s.Next(&nextT); err != nil {
+        return
+      }
+      if delta := nextT.Sub(e.Now()); delta > 0 {
+        e.Sleep(delta)
+        if e.IsEnded() {
+          return
+        }
+      } else {
+        continue
+      }
+      t.Do(e)
+      if e.Error() != nil {
+        return
+      }
+    }
+  })
+}
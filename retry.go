@@ -0,0 +1,93 @@
+// Copyright 2013 Travis Keep. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or
+// at http://opensource.org/licenses/BSD-3-Clause.
+
+package tasks
+
+import (
+  "math/rand"
+  "time"
+)
+
+// RetryPolicy controls how RetryingTask retries a failing Task.
+type RetryPolicy struct {
+
+  // MaxAttempts is the maximum number of times the task is run. A
+  // value of 1 means no retries.
+  MaxAttempts int
+
+  // ShouldRetry reports whether the task should be retried after
+  // failing with err on the given attempt, attempt being 1 for the
+  // first run. A nil ShouldRetry retries every error.
+  ShouldRetry func(err error, attempt int) bool
+
+  // Backoff is how long to wait before attempt+1, attempt being 1 for
+  // the first run. ExponentialBackoff and FixedBackoff build common
+  // choices for it.
+  Backoff func(attempt int) time.Duration
+}
+
+func (p RetryPolicy) shouldRetry(err error, attempt int) bool {
+  if p.ShouldRetry == nil {
+    return true
+  }
+  return p.ShouldRetry(err, attempt)
+}
+
+// RetryingTask returns a Task that runs t, and on error retries it
+// according to policy, sleeping with e.Sleep(policy.Backoff(attempt))
+// between attempts so that End still interrupts a pending retry.
+// RetryingTask gives up, leaving the last error recorded on e, once
+// policy.MaxAttempts is reached, policy.ShouldRetry returns false, or
+// the Execution is ended.
+func RetryingTask(t Task, policy RetryPolicy) Task {
+  return taskFunc(func(e *Execution) {
+    for attempt := 1; ; attempt++ {
+      child := childExecution(e)
+      t.Do(child)
+      err := child.Error()
+      e.mu.Lock()
+      e.now = child.now
+      e.result = child.result
+      e.mu.Unlock()
+      if err == nil {
+        return
+      }
+      if e.IsEnded() || attempt >= policy.MaxAttempts || !policy.shouldRetry(err, attempt) {
+        e.SetError(err)
+        return
+      }
+      e.Sleep(policy.Backoff(attempt))
+      if e.IsEnded() {
+        e.SetError(err)
+        return
+      }
+    }
+  })
+}
+
+// ExponentialBackoff returns a RetryPolicy.Backoff func that doubles
+// base on each attempt up to max, with jitter added as a fraction of
+// the computed delay (a jitter of 0.1 means +/-10%).
+func ExponentialBackoff(base, max time.Duration, jitter float64) func(attempt int) time.Duration {
+  return func(attempt int) time.Duration {
+    d := base << uint(attempt-1)
+    if d <= 0 || d > max {
+      d = max
+    }
+    if jitter > 0 {
+      delta := float64(d) * jitter
+      d = d - time.Duration(delta) + time.Duration(rand.Float64()*2*delta)
+    }
+    return d
+  }
+}
+
+// FixedBackoff returns a RetryPolicy.Backoff func that always waits d
+// between attempts.
+func FixedBackoff(d time.Duration) func(attempt int) time.Duration {
+  return func(attempt int) time.Duration {
+    return d
+  }
+}
@@ -0,0 +1,123 @@
+// Copyright 2013 Travis Keep. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or
+// at http://opensource.org/licenses/BSD-3-Clause.
+
+package tasks_test
+
+import (
+  "errors"
+  "github.com/keep94/tasks"
+  "testing"
+  "time"
+)
+
+var kRetryError = errors.New("tasks: retry test error")
+
+// flakyTask fails with kRetryError on its first failTimes runs, then
+// succeeds, writing its attempt number to its Execution each time.
+type flakyTask struct {
+  failTimes int
+  attempts  int
+}
+
+func (ft *flakyTask) Do(e *tasks.Execution) {
+  ft.attempts++
+  e.Write([]byte{byte(ft.attempts)})
+  if ft.attempts <= ft.failTimes {
+    e.SetError(kRetryError)
+  }
+}
+
+func TestRetryingTaskSucceedsAfterFailures(t *testing.T) {
+  ft := &flakyTask{failTimes: 2}
+  policy := tasks.RetryPolicy{MaxAttempts: 5, Backoff: tasks.FixedBackoff(0)}
+  if err := tasks.Run(tasks.RetryingTask(ft, policy)); err != nil {
+    t.Errorf("Expected no error, got %v", err)
+  }
+  if ft.attempts != 3 {
+    t.Errorf("Expected 3 attempts, got %d", ft.attempts)
+  }
+}
+
+func TestRetryingTaskGivesUpAtMaxAttempts(t *testing.T) {
+  ft := &flakyTask{failTimes: 100}
+  policy := tasks.RetryPolicy{MaxAttempts: 3, Backoff: tasks.FixedBackoff(0)}
+  err := tasks.Run(tasks.RetryingTask(ft, policy))
+  if err != kRetryError {
+    t.Errorf("Expected kRetryError, got %v", err)
+  }
+  if ft.attempts != 3 {
+    t.Errorf("Expected 3 attempts, got %d", ft.attempts)
+  }
+}
+
+func TestRetryingTaskShouldRetryFalse(t *testing.T) {
+  ft := &flakyTask{failTimes: 100}
+  policy := tasks.RetryPolicy{
+    MaxAttempts: 5,
+    ShouldRetry: func(err error, attempt int) bool { return false },
+    Backoff:     tasks.FixedBackoff(0),
+  }
+  err := tasks.Run(tasks.RetryingTask(ft, policy))
+  if err != kRetryError {
+    t.Errorf("Expected kRetryError, got %v", err)
+  }
+  if ft.attempts != 1 {
+    t.Errorf("Expected 1 attempt, got %d", ft.attempts)
+  }
+}
+
+func TestRetryingTaskAbortsWhenEnded(t *testing.T) {
+  ft := &flakyTask{failTimes: 100}
+  policy := tasks.RetryPolicy{MaxAttempts: 100, Backoff: tasks.FixedBackoff(time.Hour)}
+  e := tasks.Start(tasks.RetryingTask(ft, policy))
+  e.End()
+  <-e.Done()
+  if ft.attempts != 1 {
+    t.Errorf("Expected exactly 1 attempt before abort, got %d", ft.attempts)
+  }
+  if e.Error() != kRetryError {
+    t.Errorf("Expected kRetryError, got %v", e.Error())
+  }
+}
+
+func TestRetryingTaskRecordsLastAttemptResult(t *testing.T) {
+  ft := &flakyTask{failTimes: 2}
+  policy := tasks.RetryPolicy{MaxAttempts: 5, Backoff: tasks.FixedBackoff(0)}
+  store := tasks.NewMemStore()
+  e := tasks.StartWithOptions(
+      tasks.RetryingTask(ft, policy), tasks.TaskID("retry-result"), tasks.UseStore(store))
+  <-e.Done()
+  info, err := store.Get("retry-result")
+  if err != nil {
+    t.Fatalf("Get returned error: %v", err)
+  }
+  result := info.Result()
+  if len(result) != 1 || result[0] != byte(ft.attempts) {
+    t.Errorf("Expected result to reflect the last attempt (%d), got %v", ft.attempts, result)
+  }
+}
+
+func TestExponentialBackoff(t *testing.T) {
+  backoff := tasks.ExponentialBackoff(time.Second, 10*time.Second, 0)
+  if got := backoff(1); got != time.Second {
+    t.Errorf("Expected 1s for attempt 1, got %v", got)
+  }
+  if got := backoff(2); got != 2*time.Second {
+    t.Errorf("Expected 2s for attempt 2, got %v", got)
+  }
+  if got := backoff(10); got != 10*time.Second {
+    t.Errorf("Expected backoff to cap at 10s, got %v", got)
+  }
+}
+
+func TestFixedBackoff(t *testing.T) {
+  backoff := tasks.FixedBackoff(5 * time.Second)
+  if got := backoff(1); got != 5*time.Second {
+    t.Errorf("Expected 5s, got %v", got)
+  }
+  if got := backoff(9); got != 5*time.Second {
+    t.Errorf("Expected 5s, got %v", got)
+  }
+}
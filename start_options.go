@@ -0,0 +1,100 @@
+// Copyright 2013 Travis Keep. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or
+// at http://opensource.org/licenses/BSD-3-Clause.
+
+package tasks
+
+import (
+  "context"
+  "strconv"
+  "sync/atomic"
+  "time"
+)
+
+// Option configures a task started with StartWithOptions.
+type Option interface {
+  apply(*startOptions)
+}
+
+type startOptions struct {
+  taskID    string
+  retention time.Duration
+  store     Store
+}
+
+type optionFunc func(*startOptions)
+
+func (f optionFunc) apply(o *startOptions) {
+  f(o)
+}
+
+// TaskID sets the id a task started with StartWithOptions is recorded
+// under. If omitted, an id is generated.
+func TaskID(id string) Option {
+  return optionFunc(func(o *startOptions) {
+    o.taskID = id
+  })
+}
+
+// Retention sets how long a task's TaskInfo remains in its Store after
+// completion before the background sweeper removes it. If omitted, or
+// zero, the record is kept indefinitely.
+func Retention(d time.Duration) Option {
+  return optionFunc(func(o *startOptions) {
+    o.retention = d
+  })
+}
+
+// UseStore sets the Store that a task started with StartWithOptions
+// records its TaskInfo in. Without it, StartWithOptions behaves like
+// Start and nothing is recorded.
+func UseStore(s Store) Option {
+  return optionFunc(func(o *startOptions) {
+    o.store = s
+  })
+}
+
+var taskIDSeq uint64
+
+func nextTaskID() string {
+  return strconv.FormatUint(atomic.AddUint64(&taskIDSeq, 1), 10)
+}
+
+// StartWithOptions starts t running in its own goroutine, like Start,
+// but additionally records its outcome as a TaskInfo in the Store given
+// by UseStore once it completes. TaskID gives the record's id, and
+// Retention bounds how long it stays in the Store afterward.
+func StartWithOptions(t Task, opts ...Option) *Execution {
+  so := startOptions{taskID: nextTaskID()}
+  for _, opt := range opts {
+    opt.apply(&so)
+  }
+  e := newExecution(context.Background(), realClock{}, true)
+  go func() {
+    t.Do(e)
+    if so.store != nil {
+      info := TaskInfo{
+        ID:          so.taskID,
+        Err:         e.Error(),
+        CompletedAt: e.clk.Now(),
+        result:      e.resultBytes(),
+      }
+      so.store.Record(info)
+      if so.retention > 0 {
+        scheduleSweep(so.store, so.taskID, so.retention)
+      }
+    }
+    e.markDone()
+  }()
+  return e
+}
+
+// scheduleSweep arranges for id to be removed from s once retention has
+// elapsed, the background half of the retention policy StartWithOptions
+// documents.
+func scheduleSweep(s Store, id string, retention time.Duration) {
+  time.AfterFunc(retention, func() {
+    s.Delete(id)
+  })
+}
@@ -0,0 +1,176 @@
+// Copyright 2013 Travis Keep. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or
+// at http://opensource.org/licenses/BSD-3-Clause.
+
+package tasks
+
+import (
+  "database/sql"
+  "errors"
+  "sync"
+  "time"
+)
+
+// ErrNotFound is returned by Store.Get when no TaskInfo is recorded
+// for the given id.
+var ErrNotFound = errors.New("tasks: task not found")
+
+// TaskInfo is a record of a single completed StartWithOptions run: its
+// id, when it finished, the terminal error if any, and the bytes
+// written to its Execution with Write.
+type TaskInfo struct {
+  ID          string
+  Err         error
+  CompletedAt time.Time
+  result      []byte
+}
+
+// Result returns the bytes the task wrote to its Execution via Write.
+func (ti TaskInfo) Result() []byte {
+  return ti.result
+}
+
+// Filter reports whether a TaskInfo should be included in the results
+// of a Store.List call.
+type Filter func(TaskInfo) bool
+
+// Store records and retrieves the TaskInfo for completed tasks started
+// with StartWithOptions.
+type Store interface {
+
+  // Record saves info, replacing any existing record with the same ID.
+  Record(info TaskInfo) error
+
+  // Get returns the TaskInfo recorded under id, or ErrNotFound if none
+  // exists.
+  Get(id string) (TaskInfo, error)
+
+  // List returns every recorded TaskInfo for which filter returns true.
+  // A nil filter matches everything.
+  List(filter Filter) ([]TaskInfo, error)
+
+  // Delete removes the TaskInfo recorded under id, if any. Delete on a
+  // missing id is a no-op.
+  Delete(id string) error
+}
+
+// NewMemStore returns a Store that keeps TaskInfo records in memory.
+// It is suitable for tests and single-process servers.
+func NewMemStore() Store {
+  return &memStore{infos: make(map[string]TaskInfo)}
+}
+
+type memStore struct {
+  mu    sync.Mutex
+  infos map[string]TaskInfo
+}
+
+func (s *memStore) Record(info TaskInfo) error {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+  s.infos[info.ID] = info
+  return nil
+}
+
+func (s *memStore) Get(id string) (TaskInfo, error) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+  info, ok := s.infos[id]
+  if !ok {
+    return TaskInfo{}, ErrNotFound
+  }
+  return info, nil
+}
+
+func (s *memStore) List(filter Filter) ([]TaskInfo, error) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+  var result []TaskInfo
+  for _, info := range s.infos {
+    if filter == nil || filter(info) {
+      result = append(result, info)
+    }
+  }
+  return result, nil
+}
+
+func (s *memStore) Delete(id string) error {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+  delete(s.infos, id)
+  return nil
+}
+
+// NewSQLStore returns a Store backed by db. The caller is responsible
+// for creating a table with the schema:
+//
+//	CREATE TABLE task_info (
+//	  id           TEXT PRIMARY KEY,
+//	  err          TEXT,
+//	  completed_at TIMESTAMP NOT NULL,
+//	  result       BLOB
+//	)
+func NewSQLStore(db *sql.DB) Store {
+  return &sqlStore{db: db}
+}
+
+type sqlStore struct {
+  db *sql.DB
+}
+
+func (s *sqlStore) Record(info TaskInfo) error {
+  var errStr sql.NullString
+  if info.Err != nil {
+    errStr = sql.NullString{String: info.Err.Error(), Valid: true}
+  }
+  _, err := s.db.Exec(
+      `REPLACE INTO task_info (id, err, completed_at, result) VALUES (?, ?, ?, ?)`,
+      info.ID, errStr, info.CompletedAt, info.result)
+  return err
+}
+
+func (s *sqlStore) Get(id string) (TaskInfo, error) {
+  var info TaskInfo
+  var errStr sql.NullString
+  row := s.db.QueryRow(
+      `SELECT id, err, completed_at, result FROM task_info WHERE id = ?`, id)
+  if err := row.Scan(&info.ID, &errStr, &info.CompletedAt, &info.result); err != nil {
+    if err == sql.ErrNoRows {
+      return TaskInfo{}, ErrNotFound
+    }
+    return TaskInfo{}, err
+  }
+  if errStr.Valid {
+    info.Err = errors.New(errStr.String)
+  }
+  return info, nil
+}
+
+func (s *sqlStore) List(filter Filter) ([]TaskInfo, error) {
+  rows, err := s.db.Query(`SELECT id, err, completed_at, result FROM task_info`)
+  if err != nil {
+    return nil, err
+  }
+  defer rows.Close()
+  var result []TaskInfo
+  for rows.Next() {
+    var info TaskInfo
+    var errStr sql.NullString
+    if err := rows.Scan(&info.ID, &errStr, &info.CompletedAt, &info.result); err != nil {
+      return nil, err
+    }
+    if errStr.Valid {
+      info.Err = errors.New(errStr.String)
+    }
+    if filter == nil || filter(info) {
+      result = append(result, info)
+    }
+  }
+  return result, rows.Err()
+}
+
+func (s *sqlStore) Delete(id string) error {
+  _, err := s.db.Exec(`DELETE FROM task_info WHERE id = ?`, id)
+  return err
+}
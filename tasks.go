@@ -0,0 +1,344 @@
+// Copyright 2013 Travis Keep. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or
+// at http://opensource.org/licenses/BSD-3-Clause.
+
+// Package tasks provides the primitives for writing and composing
+// long-running, cancelable tasks: single tasks, series and parallel
+// composites, retries, and recurring schedules driven by the recurring
+// package.
+package tasks
+
+import (
+  "context"
+  "sync"
+  "time"
+)
+
+// Task represents a unit of work that can be run by this package.
+type Task interface {
+
+  // Do performs the work of this task. e reports elapsed time,
+  // whether the task has been asked to end, and lets the task record
+  // an error and sleep in a way that End can interrupt.
+  Do(e *Execution)
+}
+
+// taskFunc adapts an ordinary function to a Task.
+type taskFunc func(e *Execution)
+
+func (f taskFunc) Do(e *Execution) {
+  f(e)
+}
+
+// Clock provides the current time. It exists so that tests can
+// substitute a fake notion of "now" without a task having to sleep
+// through real wall-clock time.
+type Clock interface {
+  Now() time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+  return time.Now()
+}
+
+// ClockForTesting is a Clock whose Now() always returns a fixed time.
+// It is meant to be paired with RunForTesting.
+type ClockForTesting struct {
+  CurrentTime time.Time
+}
+
+func (c *ClockForTesting) Now() time.Time {
+  return c.CurrentTime
+}
+
+// Execution represents a single running or completed Task. A Task
+// reports progress and errors through the Execution passed to its Do
+// method; callers control and observe that same Task through the
+// *Execution returned by Start.
+type Execution struct {
+  ctx         context.Context
+  cancel      context.CancelFunc
+  mu          sync.Mutex
+  clk         Clock
+  now         time.Time
+  err         error
+  ended       chan struct{}
+  endedClosed bool
+  done        chan struct{}
+  doneClosed  bool
+  real        bool
+  result      []byte
+}
+
+func newExecution(ctx context.Context, clk Clock, real bool) *Execution {
+  ctx, cancel := context.WithCancel(ctx)
+  return &Execution{
+    ctx:    ctx,
+    cancel: cancel,
+    clk:    clk,
+    now:    clk.Now(),
+    ended:  make(chan struct{}),
+    done:   make(chan struct{}),
+    real:   real,
+  }
+}
+
+// childExecution returns a new *Execution that shares parent's context,
+// clock, and Ended signal, starts at parent's current time, and has its
+// own Done signal; its Sleep does not advance parent's time. It
+// is used by Task wrappers, such as RetryingTask, that run an inner Task
+// through its own Execution while still honoring the parent's End and
+// Context.
+func childExecution(parent *Execution) *Execution {
+  return &Execution{
+    ctx:    parent.ctx,
+    cancel: func() {},
+    clk:    parent.clk,
+    now:    parent.Now(),
+    ended:  parent.ended,
+    done:   make(chan struct{}),
+    real:   parent.real,
+  }
+}
+
+// Context returns the context.Context associated with this Execution.
+// It is derived from the context passed to StartContext or RunContext
+// (or context.Background() under Start, Run, and RunForTesting) and is
+// canceled when End is called.
+func (e *Execution) Context() context.Context {
+  return e.ctx
+}
+
+// Now returns the current time as far as this Execution is concerned.
+// Under Start and Run this is the wall-clock time at which the
+// Execution was created, advanced by the full d of each call to Sleep;
+// it does not advance while the task runs without sleeping. Under
+// RunForTesting it starts at the fake clock's time and is likewise
+// advanced by Sleep, letting recurring tasks be tested without waiting
+// in real time.
+func (e *Execution) Now() time.Time {
+  e.mu.Lock()
+  defer e.mu.Unlock()
+  return e.now
+}
+
+// Sleep pauses the task for d, until End is called, or until this
+// Execution's Context is done, whichever comes first. Under
+// RunForTesting, Sleep does not block; it only advances the time Now
+// reports (the Clock itself is left unchanged) so that recurring
+// schedules can be driven to completion instantly.
+func (e *Execution) Sleep(d time.Duration) {
+  if d > 0 {
+    if e.real {
+      timer := time.NewTimer(d)
+      defer timer.Stop()
+      select {
+      case <-timer.C:
+      case <-e.Ended():
+      case <-e.ctx.Done():
+      }
+    }
+    e.mu.Lock()
+    e.now = e.now.Add(d)
+    e.mu.Unlock()
+  }
+}
+
+// Write appends p to the result blob recorded for this Execution when
+// it is started with StartWithOptions and a Store. It always returns
+// len(p), nil and is safe to call from the Task's own goroutine.
+func (e *Execution) Write(p []byte) (int, error) {
+  e.mu.Lock()
+  defer e.mu.Unlock()
+  e.result = append(e.result, p...)
+  return len(p), nil
+}
+
+func (e *Execution) resultBytes() []byte {
+  e.mu.Lock()
+  defer e.mu.Unlock()
+  return e.result
+}
+
+// SetError records err as the result of this execution. Only the first
+// error recorded is kept.
+func (e *Execution) SetError(err error) {
+  e.mu.Lock()
+  defer e.mu.Unlock()
+  if e.err == nil {
+    e.err = err
+  }
+}
+
+// Error returns the error recorded with SetError, or nil if none.
+func (e *Execution) Error() error {
+  e.mu.Lock()
+  defer e.mu.Unlock()
+  return e.err
+}
+
+// End asks the running Task to stop as soon as possible. End does not
+// wait for the task to actually stop; use Done for that.
+func (e *Execution) End() {
+  e.end()
+}
+
+// end closes the ended channel and cancels the context exactly once.
+// It backs both End, for callers asking a task to stop early, and
+// markDone, so that Ended is also closed when a task finishes on its
+// own instead of only when End is called explicitly.
+func (e *Execution) end() {
+  e.mu.Lock()
+  defer e.mu.Unlock()
+  if !e.endedClosed {
+    close(e.ended)
+    e.endedClosed = true
+    e.cancel()
+  }
+}
+
+// Ended returns a channel that is closed once End has been called, or
+// once the task finishes on its own.
+func (e *Execution) Ended() <-chan struct{} {
+  return e.ended
+}
+
+// IsEnded returns true if End has been called, the task has finished
+// on its own, or this Execution's Context has been canceled or has
+// passed its deadline. The latter covers a caller canceling the
+// context given to StartContext or RunContext directly, without going
+// through End, which also cancels that same Context.
+func (e *Execution) IsEnded() bool {
+  if e.ctx.Err() != nil {
+    return true
+  }
+  select {
+  case <-e.ended:
+    return true
+  default:
+    return false
+  }
+}
+
+// Done returns a channel that is closed once the task has finished,
+// whether it ran to completion or was ended early.
+func (e *Execution) Done() <-chan struct{} {
+  return e.done
+}
+
+// IsDone returns true if the task has finished.
+func (e *Execution) IsDone() bool {
+  select {
+  case <-e.done:
+    return true
+  default:
+    return false
+  }
+}
+
+func (e *Execution) markDone() {
+  e.end()
+  e.mu.Lock()
+  defer e.mu.Unlock()
+  if !e.doneClosed {
+    close(e.done)
+    e.doneClosed = true
+  }
+}
+
+// Start starts t running in its own goroutine and returns immediately
+// with an *Execution for observing and controlling it. It is equivalent
+// to StartContext(context.Background(), t).
+func Start(t Task) *Execution {
+  return StartContext(context.Background(), t)
+}
+
+// StartContext is like Start, but the returned Execution's Context is
+// derived from ctx: it is done when ctx is done or when End is called,
+// whichever comes first.
+func StartContext(ctx context.Context, t Task) *Execution {
+  e := newExecution(ctx, realClock{}, true)
+  go func() {
+    t.Do(e)
+    e.markDone()
+  }()
+  return e
+}
+
+// Run runs t synchronously on the calling goroutine and returns the
+// error it completed with, if any. It is equivalent to
+// RunContext(context.Background(), t).
+func Run(t Task) error {
+  return RunContext(context.Background(), t)
+}
+
+// RunContext is like Run, but the Execution passed to t.Do has a
+// Context derived from ctx.
+func RunContext(ctx context.Context, t Task) error {
+  e := newExecution(ctx, realClock{}, true)
+  t.Do(e)
+  e.markDone()
+  return e.Error()
+}
+
+// RunForTesting runs t synchronously using clk in place of the real
+// clock. Sleep does not block real time under RunForTesting, so tasks
+// that sleep or wait on a recurring.R complete instantly. It is meant
+// for use in tests; see TestRecurring in tasks_test.go for an example.
+func RunForTesting(t Task, clk Clock) {
+  e := newExecution(context.Background(), clk, false)
+  t.Do(e)
+  e.markDone()
+}
+
+// SeriesTasks returns a Task that runs each of tasks in order on the
+// same Execution. Each task always runs to completion; SeriesTasks
+// stops short of the remaining tasks if a task reports an error or the
+// Execution is ended.
+func SeriesTasks(taskList ...Task) Task {
+  return taskFunc(func(e *Execution) {
+    for _, t := range taskList {
+      t.Do(e)
+      if e.Error() != nil || e.IsEnded() {
+        return
+      }
+    }
+  })
+}
+
+// ParallelTasks returns a Task that runs all of tasks concurrently on
+// the same Execution, one goroutine per task, and waits for all of
+// them to finish. If more than one task reports an error, only the
+// first one recorded by the Execution survives.
+func ParallelTasks(taskList ...Task) Task {
+  return taskFunc(func(e *Execution) {
+    var wg sync.WaitGroup
+    wg.Add(len(taskList))
+    for _, t := range taskList {
+      t := t
+      go func() {
+        defer wg.Done()
+        t.Do(e)
+      }()
+    }
+    wg.Wait()
+  })
+}
+
+// RepeatingTask returns a Task that runs t up to n times in a row on
+// the same Execution. Each run always runs to completion; RepeatingTask
+// stops short of n runs if t reports an error or the Execution is
+// ended.
+func RepeatingTask(t Task, n int) Task {
+  return taskFunc(func(e *Execution) {
+    for i := 0; i < n; i++ {
+      t.Do(e)
+      if e.Error() != nil || e.IsEnded() {
+        return
+      }
+    }
+  })
+}